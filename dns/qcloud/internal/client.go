@@ -0,0 +1,213 @@
+// Package internal implements a minimal client for the DNSPod OpenAPI 3.0,
+// signed using TC3-HMAC-SHA256, covering only the actions the qcloud
+// DNS provider needs.
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHost    = "dnspod.tencentcloudapi.com"
+	defaultService = "dnspod"
+	defaultVersion = "2021-03-23"
+
+	// recordListPageSize is the page size used when walking DescribeRecordList.
+	recordListPageSize = 100
+)
+
+// Client is a DNSPod OpenAPI 3.0 client.
+type Client struct {
+	SecretID  string
+	SecretKey string
+	Region    string
+
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new Client.
+func NewClient(secretID, secretKey, region string) *Client {
+	return &Client{
+		SecretID:   secretID,
+		SecretKey:  secretKey,
+		Region:     region,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// DescribeDomainList returns all domains registered in the DNSPod account.
+func (c *Client) DescribeDomainList() ([]Domain, error) {
+	var result describeDomainListResponse
+	if err := c.call("DescribeDomainList", describeDomainListRequest{}, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Response.DomainList, nil
+}
+
+// CreateRecord creates a DNS record and returns its ID.
+func (c *Client) CreateRecord(domain, subDomain, recordType, recordLine, recordLineID, value string, ttl int) (int64, error) {
+	req := createRecordRequest{
+		Domain:       domain,
+		SubDomain:    subDomain,
+		RecordType:   recordType,
+		RecordLine:   recordLine,
+		RecordLineID: recordLineID,
+		Value:        value,
+		TTL:          ttl,
+	}
+
+	var result createRecordResponse
+	if err := c.call("CreateRecord", req, &result); err != nil {
+		return 0, err
+	}
+
+	return result.Response.RecordID, nil
+}
+
+// DescribeRecordList returns every record of domain matching subDomain and
+// recordType, walking all pages of the DescribeRecordList API.
+func (c *Client) DescribeRecordList(domain, subDomain, recordType string) ([]Record, error) {
+	var records []Record
+
+	offset := 0
+	for {
+		req := describeRecordListRequest{
+			Domain:     domain,
+			Subdomain:  subDomain,
+			RecordType: recordType,
+			Offset:     offset,
+			Limit:      recordListPageSize,
+		}
+
+		var result describeRecordListResponse
+		if err := c.call("DescribeRecordList", req, &result); err != nil {
+			// DNSPod returns ResourceNotFound.NoDataOfRecord when a domain has no matching records.
+			if isNoDataError(err) {
+				return records, nil
+			}
+			return nil, err
+		}
+
+		records = append(records, result.Response.RecordList...)
+
+		offset += len(result.Response.RecordList)
+		if len(result.Response.RecordList) == 0 || offset >= result.Response.RecordCountInfo.TotalCount {
+			break
+		}
+	}
+
+	return records, nil
+}
+
+// DeleteRecord deletes a record by ID.
+func (c *Client) DeleteRecord(domain string, recordID int64) error {
+	req := deleteRecordRequest{
+		Domain:   domain,
+		RecordID: recordID,
+	}
+
+	var result deleteRecordResponse
+	return c.call("DeleteRecord", req, &result)
+}
+
+func (c *Client) call(action string, payload interface{}, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", action, err)
+	}
+
+	req, err := c.newRequest(action, body)
+	if err != nil {
+		return err
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("qcloud: %s request failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("qcloud: failed to read %s response: %w", action, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("qcloud: %s returned HTTP %d: %s", action, resp.StatusCode, string(raw))
+	}
+
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("qcloud: failed to unmarshal %s response: %w", action, err)
+	}
+
+	var base response
+	if err := json.Unmarshal(raw, &base); err != nil {
+		return fmt.Errorf("qcloud: failed to unmarshal %s response: %w", action, err)
+	}
+
+	if base.Response.Error != nil {
+		return &APIError{action: action, code: base.Response.Error.Code, message: base.Response.Error.Message}
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(action string, body []byte) (*http.Request, error) {
+	timestamp := time.Now()
+	authorization, date := sign(c.SecretID, c.SecretKey, defaultService, defaultHost, string(body), timestamp)
+
+	endpoint := fmt.Sprintf("https://%s", defaultHost)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("qcloud: failed to create %s request: %w", action, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Host", defaultHost)
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Version", defaultVersion)
+	req.Header.Set("X-TC-Timestamp", fmt.Sprintf("%d", timestamp.Unix()))
+	req.Header.Set("X-TC-Date", date)
+	if c.Region != "" {
+		req.Header.Set("X-TC-Region", c.Region)
+	}
+
+	return req, nil
+}
+
+// APIError represents a DNSPod OpenAPI error response.
+type APIError struct {
+	action  string
+	code    string
+	message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("qcloud: %s API call failed: [%s] %s", e.action, e.code, e.message)
+}
+
+// Code returns the DNSPod API error code, e.g. "RecordCreate.SubDomainInvalid".
+func (e *APIError) Code() string {
+	return e.code
+}
+
+func isNoDataError(err error) bool {
+	var apiErr *APIError
+	if e, ok := err.(*APIError); ok {
+		apiErr = e
+	}
+	return apiErr != nil && apiErr.code == "ResourceNotFound.NoDataOfRecord"
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const algorithm = "TC3-HMAC-SHA256"
+
+// sign computes the Authorization header value for a DNSPod OpenAPI 3.0
+// request, following the TC3-HMAC-SHA256 signing process described at
+// https://cloud.tencent.com/document/api/1427/56189. It only signs
+// content-type and host, matching the canonical/signed header set built by
+// sendWithSignatureV3 in the official tencentcloud-sdk-go common client;
+// X-TC-Action is sent as a plain header but is not part of the signature.
+func sign(secretID, secretKey, service, host, payload string, timestamp time.Time) (authorization string, date string) {
+	date = timestamp.UTC().Format("2006-01-02")
+
+	canonicalHeaders := fmt.Sprintf("content-type:application/json; charset=utf-8\nhost:%s\n", host)
+	signedHeaders := "content-type;host"
+	hashedPayload := hashHex(payload)
+
+	canonicalRequest := fmt.Sprintf("POST\n/\n\n%s\n%s\n%s", canonicalHeaders, signedHeaders, hashedPayload)
+
+	credentialScope := fmt.Sprintf("%s/%s/tc3_request", date, service)
+	stringToSign := fmt.Sprintf("%s\n%d\n%s\n%s", algorithm, timestamp.Unix(), credentialScope, hashHex(canonicalRequest))
+
+	secretDate := hmacSHA256([]byte("TC3"+secretKey), date)
+	secretService := hmacSHA256(secretDate, service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	authorization = fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, secretID, credentialScope, signedHeaders, signature)
+
+	return authorization, date
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
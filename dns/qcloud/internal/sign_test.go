@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	secretID := "AKIDz8krbsJ5yKBZQpn74WFkmLPx3gnPhESA"
+	secretKey := "Gu5t9xGARNpq86cd98joQYCN3cozk1qA"
+	payload := `{"Offset":0,"Limit":20}`
+	timestamp := time.Unix(1551113065, 0)
+
+	authorization, date := sign(secretID, secretKey, defaultService, defaultHost, payload, timestamp)
+
+	expectedDate := "2019-02-25"
+	if date != expectedDate {
+		t.Errorf("date = %s, want %s", date, expectedDate)
+	}
+
+	expectedAuthorization := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3gnPhESA/2019-02-25/dnspod/tc3_request, " +
+		"SignedHeaders=content-type;host, Signature=cf31954ea9cfe2517fecf27b5ab6be8b727a125758383027c11160b0530f5ca5"
+	if authorization != expectedAuthorization {
+		t.Errorf("authorization = %s, want %s", authorization, expectedAuthorization)
+	}
+}
@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ExtractSubDomain returns the part of domain that sits below zone,
+// e.g. ExtractSubDomain("_acme-challenge.www.example.com.", "example.com.")
+// returns "_acme-challenge.www". Both domain and zone are FQDN-normalized
+// before comparison, so trailing dots are optional.
+func ExtractSubDomain(domain, zone string) (string, error) {
+	canonDomain := dns.Fqdn(domain)
+	canonZone := dns.Fqdn(zone)
+
+	if canonDomain == canonZone {
+		return "", fmt.Errorf("no subdomain because domain and zone are identical: %s", canonDomain)
+	}
+
+	if !dns.IsSubDomain(canonZone, canonDomain) {
+		return "", fmt.Errorf("%s is not a subdomain of %s", canonDomain, canonZone)
+	}
+
+	return strings.TrimSuffix(canonDomain, "."+canonZone), nil
+}
@@ -0,0 +1,59 @@
+package internal
+
+import "testing"
+
+func TestExtractSubDomain(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		domain   string
+		zone     string
+		expected string
+		wantErr  bool
+	}{
+		{
+			desc:     "simple subdomain",
+			domain:   "_acme-challenge.www.example.com.",
+			zone:     "example.com.",
+			expected: "_acme-challenge.www",
+		},
+		{
+			desc:     "apex challenge record",
+			domain:   "_acme-challenge.example.com",
+			zone:     "example.com",
+			expected: "_acme-challenge",
+		},
+		{
+			desc:    "domain and zone identical",
+			domain:  "example.com",
+			zone:    "example.com.",
+			wantErr: true,
+		},
+		{
+			desc:    "domain not under zone",
+			domain:  "_acme-challenge.example.org.",
+			zone:    "example.com.",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			subDomain, err := ExtractSubDomain(test.domain, test.zone)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ExtractSubDomain(%q, %q) expected an error, got subDomain=%q", test.domain, test.zone, subDomain)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ExtractSubDomain(%q, %q) unexpected error: %v", test.domain, test.zone, err)
+			}
+
+			if subDomain != test.expected {
+				t.Errorf("ExtractSubDomain(%q, %q) = %q, want %q", test.domain, test.zone, subDomain, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package internal
+
+// Domain is a DNSPod domain as returned by DescribeDomainList.
+type Domain struct {
+	DomainID int64  `json:"DomainId"`
+	Name     string `json:"Name"`
+}
+
+// Record is a DNSPod record as returned by DescribeRecordList.
+type Record struct {
+	RecordID int64  `json:"RecordId"`
+	Name     string `json:"Name"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	Line     string `json:"Line"`
+	LineID   string `json:"LineId"`
+	TTL      uint64 `json:"TTL"`
+}
+
+type errorInfo struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+type response struct {
+	Response struct {
+		RequestID string     `json:"RequestId"`
+		Error     *errorInfo `json:"Error,omitempty"`
+	} `json:"Response"`
+}
+
+type describeDomainListRequest struct{}
+
+type describeDomainListResponse struct {
+	Response struct {
+		RequestID       string     `json:"RequestId"`
+		Error           *errorInfo `json:"Error,omitempty"`
+		DomainList      []Domain   `json:"DomainList"`
+		DomainCountInfo struct {
+			DomainTotal int `json:"DomainTotal"`
+		} `json:"DomainCountInfo"`
+	} `json:"Response"`
+}
+
+type createRecordRequest struct {
+	Domain       string `json:"Domain"`
+	SubDomain    string `json:"SubDomain"`
+	RecordType   string `json:"RecordType"`
+	RecordLine   string `json:"RecordLine,omitempty"`
+	RecordLineID string `json:"RecordLineId,omitempty"`
+	Value        string `json:"Value"`
+	TTL          int    `json:"TTL,omitempty"`
+}
+
+type createRecordResponse struct {
+	Response struct {
+		RequestID string     `json:"RequestId"`
+		Error     *errorInfo `json:"Error,omitempty"`
+		RecordID  int64      `json:"RecordId"`
+	} `json:"Response"`
+}
+
+type describeRecordListRequest struct {
+	Domain     string `json:"Domain"`
+	Subdomain  string `json:"Subdomain,omitempty"`
+	RecordType string `json:"RecordType,omitempty"`
+	Offset     int    `json:"Offset"`
+	Limit      int    `json:"Limit"`
+}
+
+type describeRecordListResponse struct {
+	Response struct {
+		RequestID       string     `json:"RequestId"`
+		Error           *errorInfo `json:"Error,omitempty"`
+		RecordList      []Record   `json:"RecordList"`
+		RecordCountInfo struct {
+			TotalCount int `json:"TotalCount"`
+		} `json:"RecordCountInfo"`
+	} `json:"Response"`
+}
+
+type deleteRecordRequest struct {
+	Domain   string `json:"Domain"`
+	RecordID int64  `json:"RecordId"`
+}
+
+type deleteRecordResponse struct {
+	Response struct {
+		RequestID string     `json:"RequestId"`
+		Error     *errorInfo `json:"Error,omitempty"`
+	} `json:"Response"`
+}
@@ -1,4 +1,5 @@
-// Package qcloud implements a DNS provider for solving the DNS-01 challenge using qcloud cns.
+// Package qcloud implements a DNS provider for solving the DNS-01 challenge using the
+// Tencent Cloud DNSPod OpenAPI 3.0.
 package qcloud
 
 import (
@@ -6,52 +7,94 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-acme/lego/v3/challenge/dns01"
 	"github.com/go-acme/lego/v3/platform/config/env"
-	cns "github.com/go-http/qcloud-cns"
+	"github.com/ttys3/lego-providers/dns/qcloud/internal"
+)
+
+const (
+	defaultRegion             = "ap-guangzhou"
+	defaultLine               = "默认"
+	defaultSequentialDuration = 60 * time.Second
+
+	retryMaxAttempts = 4
+	retryBaseDelay   = 500 * time.Millisecond
 )
 
 // Config is used to configure the creation of the DNSProvider
 type Config struct {
 	SecretId           string
 	SecretKey          string
+	Region             string
 	TTL                int
 	PropagationTimeout time.Duration
 	PollingInterval    time.Duration
 	HTTPClient         *http.Client
+
+	// Line is the DNSPod record line to create TXT records on, e.g. "默认" or "Default".
+	// Accounts on VIP plans may instead set LineID to target a specific ISP line by its
+	// numeric identifier.
+	Line   string
+	LineID string
+
+	// SubDomainOverride forces the record subdomain used for a given zone, keyed by the
+	// zone name without a trailing dot (e.g. "example.com"). Useful when _acme-challenge is
+	// CNAME-delegated to a different subdomain than the one lego computes.
+	SubDomainOverride map[string]string
+
+	// SequentialDuration is the minimum interval lego's sequential solver leaves between
+	// Present/CleanUp calls, so that multiple _acme-challenge TXT records for the same
+	// zone (e.g. for a SAN certificate) aren't created concurrently.
+	SequentialDuration time.Duration
 }
 
 // NewDefaultConfig returns a default configuration for the DNSProvider
 func NewDefaultConfig() *Config {
+	line := env.GetOrFile("QCLOUD_LINE")
+	if line == "" {
+		line = defaultLine
+	}
+
 	return &Config{
+		Region:             env.GetOrDefaultString("TENCENTCLOUD_REGION", defaultRegion),
 		TTL:                env.GetOrDefaultInt("QCLOUD_TTL", 600),
 		PropagationTimeout: env.GetOrDefaultSecond("QCLOUD_PROPAGATION_TIMEOUT", dns01.DefaultPropagationTimeout),
 		PollingInterval:    env.GetOrDefaultSecond("QCLOUD_POLLING_INTERVAL", dns01.DefaultPollingInterval),
 		HTTPClient: &http.Client{
 			Timeout: env.GetOrDefaultSecond("QCLOUD_HTTP_TIMEOUT", 0),
 		},
+		Line:               line,
+		LineID:             env.GetOrFile("QCLOUD_LINE_ID"),
+		SequentialDuration: env.GetOrDefaultSecond("QCLOUD_SEQUENTIAL_DURATION", defaultSequentialDuration),
 	}
 }
 
 // DNSProvider is an implementation of the challenge.Provider interface.
 type DNSProvider struct {
 	config *Config
-	client *cns.Client
+	client *internal.Client
+
+	// domains caches DescribeDomainList for the lifetime of the DNSProvider, since a
+	// single issuance may call getHostedZone once per SAN entry.
+	domainsOnce sync.Once
+	domains     []internal.Domain
+	domainsErr  error
 }
 
 // NewDNSProvider returns a DNSProvider instance configured for qcloud.
-// Credentials must be passed in the environment variables: QCLOUD_SECRET_ID and QCLOUD_SECRET_KEY
+// Credentials must be passed in the environment variables: TENCENTCLOUD_SECRET_ID and TENCENTCLOUD_SECRET_KEY
 func NewDNSProvider() (*DNSProvider, error) {
-	values, err := env.Get("QCLOUD_SECRET_ID", "QCLOUD_SECRET_KEY")
+	values, err := env.Get("TENCENTCLOUD_SECRET_ID", "TENCENTCLOUD_SECRET_KEY")
 	if err != nil {
-		return nil, fmt.Errorf("qcloud cns: %w", err)
+		return nil, fmt.Errorf("qcloud: %w", err)
 	}
 
 	config := NewDefaultConfig()
-	config.SecretId = values["QCLOUD_SECRET_ID"]
-	config.SecretKey = values["QCLOUD_SECRET_KEY"]
+	config.SecretId = values["TENCENTCLOUD_SECRET_ID"]
+	config.SecretKey = values["TENCENTCLOUD_SECRET_KEY"]
 
 	return NewDNSProviderConfig(config)
 }
@@ -59,42 +102,45 @@ func NewDNSProvider() (*DNSProvider, error) {
 // NewDNSProviderConfig return a DNSProvider instance configured for qcloud.
 func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 	if config == nil {
-		return nil, errors.New("qcloud cns: the configuration of the DNS provider is nil")
+		return nil, errors.New("qcloud: the configuration of the DNS provider is nil")
 	}
 
 	if config.SecretKey == "" {
-		return nil, fmt.Errorf("qcloud cns: credentials missing")
+		return nil, fmt.Errorf("qcloud: credentials missing")
 	}
 
-	client := cns.New(config.SecretId, config.SecretKey)
-	//client.HttpClient = config.HTTPClient
+	client := internal.NewClient(config.SecretId, config.SecretKey, config.Region)
+	if config.HTTPClient != nil {
+		client.HTTPClient = config.HTTPClient
+	}
 
 	return &DNSProvider{client: client, config: config}, nil
 }
 
 // Present creates a TXT record to fulfill the dns-01 challenge.
 func (d *DNSProvider) Present(domain, token, keyAuth string) error {
-	/*
-	fixup for wildcard domain
-	if it is a wildcard domain, the fqdn will be:
-	_acme-challenge.*.example.com.
-	then the subdomain name to create will be: _acme-challenge.*
-	qcloud does not allow this name to be created, and will return error:
-	[4000](RecordCreate.SubDomainInvalid): (810422)子域名不正确 子域名不正确
-	 */
-	if domain[:2] == "*." {
-		domain = domain[2:]
-	}
+	// qcloud rejects record names that still carry the wildcard label, e.g. "_acme-challenge.*"
+	// for [4000](RecordCreate.SubDomainInvalid). Strip it so "*.example.com" maps onto the zone
+	// itself, same as requesting a certificate for the apex.
+	domain = strings.TrimPrefix(domain, "*.")
+
 	fqdn, value := dns01.GetRecord(domain, keyAuth)
 	_, zoneName, err := d.getHostedZone(domain)
 	if err != nil {
 		return err
 	}
 
-	recordAttributes := d.newTxtRecord(zoneName, fqdn, value, d.config.TTL)
-	_, err = d.client.RecordCreate(zoneName, *recordAttributes)
+	record, err := d.newTxtRecord(zoneName, fqdn, value, d.config.TTL)
+	if err != nil {
+		return err
+	}
+
+	err = withRetry(func() error {
+		_, err := d.client.CreateRecord(zoneName, record.Name, "TXT", record.Line, record.LineID, record.Value, record.TTL)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("qcloud cns: RecordCreate() API call failed: %v", err)
+		return fmt.Errorf("qcloud: CreateRecord() API call failed: %w", err)
 	}
 
 	return nil
@@ -102,10 +148,8 @@ func (d *DNSProvider) Present(domain, token, keyAuth string) error {
 
 // CleanUp removes the TXT record matching the specified parameters.
 func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
-	// fixup for wildcard domain
-	if domain[:2] == "*." {
-		domain = domain[2:]
-	}
+	domain = strings.TrimPrefix(domain, "*.")
+
 	fqdn, _ := dns01.GetRecord(domain, keyAuth)
 
 	records, err := d.findTxtRecords(domain, fqdn)
@@ -115,16 +159,19 @@ func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
 
 	_, zoneName, err := d.getHostedZone(domain)
 	if err != nil {
-		return  fmt.Errorf("CleanUp(): getHostedZone err: %w", err)
+		return fmt.Errorf("CleanUp(): getHostedZone err: %w", err)
 	}
-	//fmt.Printf("CleanUp(): zoneName: %s\n", zoneName)
 
-	for _, rec := range records {
-		err := d.client.RecordDelete(zoneName, rec.Id)
+	for _, record := range records {
+		recordID := record.RecordID
+		err := withRetry(func() error {
+			return d.client.DeleteRecord(zoneName, recordID)
+		})
 		if err != nil {
-			return fmt.Errorf("CleanUp(): qcloud cns: RecordDelete err: %w", err)
+			return fmt.Errorf("CleanUp(): qcloud: DeleteRecord() API call failed: %w", err)
 		}
 	}
+
 	return nil
 }
 
@@ -134,74 +181,152 @@ func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
 	return d.config.PropagationTimeout, d.config.PollingInterval
 }
 
-func (d *DNSProvider) getHostedZone(domain string) (string, string, error) {
-	zones, err := d.client.DomainList()
+// Sequential enables lego's sequential challenge solver, which serializes Present/CleanUp
+// calls by at least this interval so that concurrent SAN entries don't race each other's
+// _acme-challenge TXT record creation in the same zone.
+func (d *DNSProvider) Sequential() time.Duration {
+	return d.config.SequentialDuration
+}
+
+// domainList returns the account's DescribeDomainList result, fetching it at most once
+// for the lifetime of the DNSProvider.
+func (d *DNSProvider) domainList() ([]internal.Domain, error) {
+	d.domainsOnce.Do(func() {
+		d.domains, d.domainsErr = d.client.DescribeDomainList()
+	})
+
+	return d.domains, d.domainsErr
+}
+
+func (d *DNSProvider) getHostedZone(domain string) (int64, string, error) {
+	domains, err := d.domainList()
 	if err != nil {
-		return "", "", fmt.Errorf("qcloud cns: DomainList() API call failed: %v", err)
+		return 0, "", fmt.Errorf("qcloud: DescribeDomainList() API call failed: %w", err)
 	}
 
 	authZone, err := dns01.FindZoneByFqdn(dns01.ToFqdn(domain))
 	if err != nil {
-		return "", "", err
+		return 0, "", err
 	}
 
-	var hostedZone cns.Domain
-	for _, zone := range zones {
-		if zone.Name == dns01.UnFqdn(authZone) {
-			hostedZone = zone
+	zoneName := dns01.UnFqdn(authZone)
+
+	for _, hostedZone := range domains {
+		if hostedZone.Name == zoneName {
+			return hostedZone.DomainID, hostedZone.Name, nil
 		}
 	}
 
-	if hostedZone.Id == 0 {
-		return "", "", fmt.Errorf("getHostedZone: zone %s not found in qcloud cns for domain %s", authZone, domain)
+	return 0, "", fmt.Errorf("getHostedZone: zone %s not found in qcloud dnspod for domain %s", authZone, domain)
+}
+
+// findTxtRecords walks every page of DescribeRecordList for domain's zone and
+// returns the records matching fqdn, so cleanup works even when the zone
+// holds more TXT records than fit in a single API page.
+func (d *DNSProvider) findTxtRecords(domain, fqdn string) ([]internal.Record, error) {
+	_, zoneName, err := d.getHostedZone(domain)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf("%v", hostedZone.Id), hostedZone.Name, nil
-}
+	recordName, err := d.recordName(fqdn, zoneName)
+	if err != nil {
+		return nil, err
+	}
 
-func (d *DNSProvider) newTxtRecord(zone, fqdn, value string, ttl int) *cns.Record {
-	//fmt.Printf("\nzone: %v, fqdn: %v, value: %v\n", zone, fqdn, value)
-	//zone: mydomain.com, fqdn: _acme-challenge.mydomain.com., value: ADw2sEd82DUgXcQ9hNBZThJs7zVJkR5v9JeSbAb9mZY---
+	allRecords, err := d.client.DescribeRecordList(zoneName, recordName, "TXT")
+	if err != nil {
+		return nil, fmt.Errorf("qcloud: DescribeRecordList() API call failed: %w", err)
+	}
 
-	name := d.extractRecordName(fqdn, zone)
-	//fmt.Printf("\nsubdomain name: %v\n", name)
+	var records []internal.Record
+	for _, record := range allRecords {
+		if record.Name == recordName && d.matchesLine(record) {
+			records = append(records, record)
+		}
+	}
 
-	return &cns.Record{
-		Type:  "TXT",
-		Name:  name,
-		Value: value,
-		Line:  "默认",
-		Ttl:   ttl,
+	return records, nil
+}
+
+// matchesLine reports whether record belongs to the line (or LineID) configured on the
+// provider, so CleanUp never deletes a TXT record that belongs to a different line/view.
+func (d *DNSProvider) matchesLine(record internal.Record) bool {
+	if d.config.LineID != "" {
+		return record.LineID == d.config.LineID
+	}
+	if d.config.Line != "" {
+		return record.Line == d.config.Line
 	}
+	return true
 }
 
-func (d *DNSProvider) findTxtRecords(domain, fqdn string) ([]cns.Record, error) {
-	_, zoneName, err := d.getHostedZone(domain)
+// txtRecord holds the parameters needed to create or match a qcloud TXT record.
+type txtRecord struct {
+	Name   string
+	Value  string
+	Line   string
+	LineID string
+	TTL    int
+}
+
+func (d *DNSProvider) newTxtRecord(zone, fqdn, value string, ttl int) (*txtRecord, error) {
+	name, err := d.recordName(fqdn, zone)
 	if err != nil {
 		return nil, err
 	}
 
-	var records []cns.Record
-	result, err := d.client.RecordList(zoneName)
-	if err != nil {
-		return records, fmt.Errorf("qcloud cns: RecordList() API call has failed: %v", err)
+	return &txtRecord{Name: name, Value: value, Line: d.config.Line, LineID: d.config.LineID, TTL: ttl}, nil
+}
+
+// recordName returns the qcloud record name to use for fqdn in zone, honoring any
+// SubDomainOverride configured for that zone before falling back to extractRecordName.
+func (d *DNSProvider) recordName(fqdn, zone string) (string, error) {
+	if override, ok := d.config.SubDomainOverride[dns01.UnFqdn(zone)]; ok {
+		return override, nil
 	}
 
-	recordName := d.extractRecordName(fqdn, zoneName)
+	return d.extractRecordName(fqdn, zone)
+}
 
-	for _, record := range result {
-		if record.Name == recordName {
-			records = append(records, record)
+// extractRecordName returns the qcloud record name (the subdomain relative to zone) for fqdn,
+// e.g. "_acme-challenge" for an apex domain or "_acme-challenge.www" for a subdomain.
+func (d *DNSProvider) extractRecordName(fqdn, zone string) (string, error) {
+	return internal.ExtractSubDomain(fqdn, zone)
+}
+
+// withRetry retries action with exponential backoff, up to retryMaxAttempts, but only for
+// DNSPod API error codes considered transient (rate limiting). Non-retryable errors, such as
+// RecordCreate.SubDomainInvalid, are returned immediately.
+func withRetry(action func() error) error {
+	delay := retryBaseDelay
+
+	var err error
+	for attempt := 1; attempt <= retryMaxAttempts; attempt++ {
+		err = action()
+		if err == nil {
+			return nil
 		}
+
+		var apiErr *internal.APIError
+		if !errors.As(err, &apiErr) || !isRetryableCode(apiErr.Code()) {
+			return err
+		}
+
+		if attempt == retryMaxAttempts {
+			break
+		}
+
+		time.Sleep(delay)
+		delay *= 2
 	}
 
-	return records, nil
+	return err
 }
 
-func (d *DNSProvider) extractRecordName(fqdn, domain string) string {
-	name := dns01.UnFqdn(fqdn)
-	if idx := strings.Index(name, "."+domain); idx != -1 {
-		return name[:idx]
-	}
-	return name
+// isRetryableCode reports whether a DNSPod OpenAPI 3.0 error code is transient and worth
+// retrying. RequestLimitExceeded is the only code the official tencentcloud-sdk-go common
+// client retries on; matching on the "LimitExceeded" suffix also covers that case.
+func isRetryableCode(code string) bool {
+	return strings.HasSuffix(code, "LimitExceeded")
 }
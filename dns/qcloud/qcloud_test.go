@@ -0,0 +1,57 @@
+package qcloud
+
+import "testing"
+
+func TestDNSProviderExtractRecordName(t *testing.T) {
+	d := &DNSProvider{config: NewDefaultConfig()}
+
+	testCases := []struct {
+		desc     string
+		fqdn     string
+		zone     string
+		expected string
+		wantErr  bool
+	}{
+		{
+			desc:     "subdomain challenge",
+			fqdn:     "_acme-challenge.www.example.com.",
+			zone:     "example.com.",
+			expected: "_acme-challenge.www",
+		},
+		{
+			// Present/CleanUp trim the "*." label from a wildcard-of-apex domain before
+			// computing fqdn, so this is also the shape produced for "*.example.com".
+			desc:     "apex challenge",
+			fqdn:     "_acme-challenge.example.com.",
+			zone:     "example.com.",
+			expected: "_acme-challenge",
+		},
+		{
+			desc:    "fqdn not under zone",
+			fqdn:    "_acme-challenge.example.org.",
+			zone:    "example.com.",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			name, err := d.extractRecordName(test.fqdn, test.zone)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("extractRecordName(%q, %q) expected an error, got name=%q", test.fqdn, test.zone, name)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("extractRecordName(%q, %q) unexpected error: %v", test.fqdn, test.zone, err)
+			}
+
+			if name != test.expected {
+				t.Errorf("extractRecordName(%q, %q) = %q, want %q", test.fqdn, test.zone, name, test.expected)
+			}
+		})
+	}
+}